@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Manifest describes the contents of an archive produced by Archive.
+type Manifest struct {
+	CreatedAt time.Time         `json:"created_at"`
+	Before    string            `json:"before"`
+	Users     map[string]string `json:"users"`
+	Channels  []ManifestChannel `json:"channels"`
+}
+
+// ManifestChannel records per-channel metadata alongside the archive.
+type ManifestChannel struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Messages int    `json:"messages"`
+	Files    int    `json:"files"`
+}
+
+// archivedMessage is a message plus its thread replies, as written to
+// a channel's messages.json.
+type archivedMessage struct {
+	slack.Message
+	Replies []slack.Message `json:"replies,omitempty"`
+}
+
+// Archive writes every candidate message and file to dir before they are
+// deleted, so users have a compliant backup of what's about to be removed.
+//
+// The on-disk layout is:
+//
+//	dir/manifest.json
+//	dir/<channel-id>/messages.json
+//	dir/files/<file-id>-<name>
+func (s *SlackClean) Archive(dir string, users map[string]string, channels []slack.Channel, messages []slack.Message, files []slack.File) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		panic(err)
+	}
+
+	filesDir := filepath.Join(dir, "files")
+	if err := os.MkdirAll(filesDir, 0o755); err != nil {
+		panic(err)
+	}
+
+	byChannel := make(map[string][]slack.Message)
+	for _, m := range messages {
+		byChannel[m.Channel] = append(byChannel[m.Channel], m)
+	}
+
+	byChannelFiles := make(map[string]int)
+	for _, f := range files {
+		for _, ch := range f.Channels {
+			byChannelFiles[ch]++
+		}
+	}
+
+	manifest := Manifest{
+		CreatedAt: time.Now(),
+		Before:    s.beforeTS,
+		Users:     users,
+	}
+
+	for _, c := range channels {
+		msgs := byChannel[c.ID]
+		if err := s.archiveChannelMessages(dir, c.ID, msgs); err != nil {
+			panic(err)
+		}
+
+		manifest.Channels = append(manifest.Channels, ManifestChannel{
+			ID:       c.ID,
+			Name:     c.Name,
+			Messages: len(msgs),
+			Files:    byChannelFiles[c.ID],
+		})
+	}
+
+	fmt.Printf("Downloading %d files into %s\n", len(files), filesDir)
+	for i, f := range files {
+		if err := s.downloadFile(filesDir, f); err != nil {
+			fmt.Printf("%04d. Failed to download file %s: %s\n", i+1, f.Name, err)
+			continue
+		}
+		fmt.Printf("%04d. Archived file %s\n", i+1, f.Name)
+	}
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(manifestPath, b, 0o644); err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("Wrote archive manifest to %s\n", manifestPath)
+}
+
+// archiveChannelMessages groups messages by thread parent and writes them,
+// nesting replies under their parent, to dir/<channelID>/messages.json.
+// Replies whose parent is not among messages are written at the top level.
+func (s *SlackClean) archiveChannelMessages(dir, channelID string, messages []slack.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	channelDir := filepath.Join(dir, channelID)
+	if err := os.MkdirAll(channelDir, 0o755); err != nil {
+		return err
+	}
+
+	byParent := make(map[string][]slack.Message)
+	var roots []slack.Message
+
+	for _, m := range messages {
+		if m.ThreadTimestamp != "" && m.ThreadTimestamp != m.Timestamp {
+			byParent[m.ThreadTimestamp] = append(byParent[m.ThreadTimestamp], m)
+			continue
+		}
+		roots = append(roots, m)
+	}
+
+	archived := make([]archivedMessage, 0, len(roots))
+	rootTS := make(map[string]bool, len(roots))
+	for _, m := range roots {
+		rootTS[m.Timestamp] = true
+		archived = append(archived, archivedMessage{
+			Message: m,
+			Replies: byParent[m.Timestamp],
+		})
+	}
+
+	for _, m := range messages {
+		if m.ThreadTimestamp != "" && m.ThreadTimestamp != m.Timestamp && !rootTS[m.ThreadTimestamp] {
+			archived = append(archived, archivedMessage{Message: m})
+		}
+	}
+
+	b, err := json.MarshalIndent(archived, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(channelDir, "messages.json"), b, 0o644)
+}
+
+// downloadFile fetches f's private URL using the authenticated token and
+// writes it into dir.
+func (s *SlackClean) downloadFile(dir string, f slack.File) error {
+	name := fmt.Sprintf("%s-%s", f.ID, filepath.Base(f.Name))
+	out, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return s.c.get().GetFile(f.URLPrivateDownload, out)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord is one line of the audit log, written for every delete
+// attempt. Text is never retained, only a hash of it, so the log can
+// prove a deletion happened for compliance/GDPR purposes without holding
+// on to the content that was deleted.
+type AuditRecord struct {
+	TS          time.Time `json:"ts"`
+	Action      string    `json:"action"` // delete_message | delete_file
+	ChannelID   string    `json:"channel_id"`
+	ChannelName string    `json:"channel_name,omitempty"`
+	MsgTS       string    `json:"msg_ts,omitempty"`
+	User        string    `json:"user,omitempty"`
+	TextHash    string    `json:"text_hash,omitempty"`
+	Bytes       int       `json:"bytes,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DryRun      bool      `json:"dry_run"`
+}
+
+// AuditSummary is the final event written at the end of a run.
+type AuditSummary struct {
+	TS              time.Time `json:"ts"`
+	Action          string    `json:"action"` // run_summary
+	Messages        int       `json:"messages"`
+	Files           int       `json:"files"`
+	Errors          int       `json:"errors"`
+	Duration        string    `json:"duration"`
+	RateLimitHits   int       `json:"rate_limit_hits"`
+	RateLimitWaited string    `json:"rate_limit_waited"`
+}
+
+// AuditLogger appends AuditRecords to a JSONL file and, if configured,
+// POSTs the same record to a webhook.
+type AuditLogger struct {
+	mu      sync.Mutex
+	f       *os.File
+	webhook string
+	start   time.Time
+	wg      sync.WaitGroup
+}
+
+// NewAuditLogger opens (creating/appending to) path for the log, and
+// arms webhook delivery if webhook is non-empty. Either may be empty to
+// disable that sink.
+func NewAuditLogger(path, webhook string) (*AuditLogger, error) {
+	a := &AuditLogger{webhook: webhook, start: time.Now()}
+
+	if path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		a.f = f
+	}
+
+	return a, nil
+}
+
+func (a *AuditLogger) write(v interface{}) {
+	if a == nil {
+		return
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		fmt.Printf("audit: failed to marshal record: %s\n", err)
+		return
+	}
+
+	a.mu.Lock()
+	if a.f != nil {
+		if _, err := a.f.Write(append(b, '\n')); err != nil {
+			fmt.Printf("audit: failed to write record: %s\n", err)
+		}
+	}
+	a.mu.Unlock()
+
+	if a.webhook != "" {
+		a.wg.Add(1)
+		go a.post(b)
+	}
+}
+
+func (a *AuditLogger) post(b []byte) {
+	defer a.wg.Done()
+
+	resp, err := http.Post(a.webhook, "application/json", bytes.NewReader(b))
+	if err != nil {
+		fmt.Printf("audit: failed to POST to webhook: %s\n", err)
+		return
+	}
+	resp.Body.Close()
+}
+
+// RecordMessage logs one chat.delete attempt. Only a hash of the message
+// text is kept, never the text itself.
+func (a *AuditLogger) RecordMessage(m AuditRecord) {
+	if a == nil {
+		return
+	}
+	m.TS = time.Now()
+	m.Action = "delete_message"
+	a.write(m)
+}
+
+// RecordFile logs one files.delete attempt.
+func (a *AuditLogger) RecordFile(f AuditRecord) {
+	if a == nil {
+		return
+	}
+	f.TS = time.Now()
+	f.Action = "delete_file"
+	a.write(f)
+}
+
+// Summary logs the final run_summary event.
+func (a *AuditLogger) Summary(messages, files, errs int, rateLimitHits int, rateLimitWaited time.Duration) {
+	if a == nil {
+		return
+	}
+
+	a.write(AuditSummary{
+		TS:              time.Now(),
+		Action:          "run_summary",
+		Messages:        messages,
+		Files:           files,
+		Errors:          errs,
+		Duration:        time.Since(a.start).Round(time.Millisecond).String(),
+		RateLimitHits:   rateLimitHits,
+		RateLimitWaited: rateLimitWaited.Round(time.Millisecond).String(),
+	})
+}
+
+// Close waits for any in-flight webhook deliveries to finish, then closes
+// the underlying log file, if any.
+func (a *AuditLogger) Close() error {
+	if a == nil {
+		return nil
+	}
+
+	a.wg.Wait()
+
+	if a.f == nil {
+		return nil
+	}
+	return a.f.Close()
+}
+
+func textHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthProvider supplies the token used to talk to the Slack API. It lets
+// the rest of SlackClean stay agnostic of how that token is obtained or
+// kept fresh across a long-running cleanup that outlasts a single
+// token's one-hour TTL.
+type AuthProvider interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticTokenProvider returns a fixed token, e.g. a user (xoxp-) or bot
+// (xoxb-) token passed via --token. See the scope matrix in main() for
+// which scopes each token type needs.
+type StaticTokenProvider string
+
+func (p StaticTokenProvider) Token(_ context.Context) (string, error) {
+	return string(p), nil
+}
+
+// storedToken is the on-disk representation of a refreshable token, kept
+// in a TokenStore between runs.
+type storedToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// TokenStore persists a refreshable token across runs.
+type TokenStore interface {
+	Load() (storedToken, error)
+	Save(storedToken) error
+}
+
+// FileTokenStore keeps the token in a single file on disk. It's a
+// deliberately simple default; swap in a keyring-backed TokenStore if you
+// don't want refresh tokens sitting in a plain file.
+type FileTokenStore struct {
+	Path string
+}
+
+func (f FileTokenStore) Load() (storedToken, error) {
+	var t storedToken
+
+	b, err := os.ReadFile(f.Path)
+	if err != nil {
+		return t, err
+	}
+
+	err = json.Unmarshal(b, &t)
+	return t, err
+}
+
+func (f FileTokenStore) Save(t storedToken) error {
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.Path, b, 0o600)
+}
+
+// OAuthRefreshProvider keeps a rotating token fresh by exchanging its
+// refresh token via oauth.v2.access whenever the cached access token is at
+// (or near) its TTL, or the API reports it as expired.
+type OAuthRefreshProvider struct {
+	ClientID     string
+	ClientSecret string
+	Store        TokenStore
+
+	mu      sync.Mutex
+	current storedToken
+}
+
+func NewOAuthRefreshProvider(clientID, clientSecret string, store TokenStore) (*OAuthRefreshProvider, error) {
+	p := &OAuthRefreshProvider{ClientID: clientID, ClientSecret: clientSecret, Store: store}
+
+	t, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading token store: %w", err)
+	}
+	p.current = t
+
+	return p, nil
+}
+
+func (p *OAuthRefreshProvider) Token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.current.AccessToken != "" && time.Now().Before(p.current.ExpiresAt.Add(-1*time.Minute)) {
+		return p.current.AccessToken, nil
+	}
+
+	return p.refresh(ctx)
+}
+
+// refresh exchanges the current refresh token for a new access token via
+// oauth.v2.access (grant_type=refresh_token), as documented at
+// https://api.slack.com/authentication/rotation
+func (p *OAuthRefreshProvider) refresh(ctx context.Context) (string, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {p.current.RefreshToken},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://slack.com/api/oauth.v2.access", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		OK           bool   `json:"ok"`
+		Error        string `json:"error"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if !out.OK {
+		return "", fmt.Errorf("oauth.v2.access: %s", out.Error)
+	}
+
+	p.current = storedToken{
+		AccessToken:  out.AccessToken,
+		RefreshToken: out.RefreshToken,
+		ExpiresAt:    time.Now().Add(time.Duration(out.ExpiresIn) * time.Second),
+	}
+
+	if err := p.Store.Save(p.current); err != nil {
+		return "", fmt.Errorf("saving refreshed token: %w", err)
+	}
+
+	return p.current.AccessToken, nil
+}
+
+// isTokenExpiredErr reports whether err is Slack's token_expired error, the
+// signal that a rotating token needs an oauth.v2.access refresh.
+func isTokenExpiredErr(err error) bool {
+	return err != nil && err.Error() == "token_expired"
+}
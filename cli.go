@@ -2,11 +2,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/slack-go/slack"
@@ -14,12 +16,14 @@ import (
 )
 
 func main() {
-	// Slack OAuth Access Token beginning with `xoxp-`.
+	// Slack token, either a user token (`xoxp-`) or a bot token (`xoxb-`).
 	// To get a token you'll need to create a new Slack app and add
 	// it to your workspace:
 	// https://api.slack.com/apps
 	//
-	// You'll also need to give your app the correct scopes (permissions):
+	// You'll also need to give your app the correct scopes (permissions).
+	// User tokens need the user-scoped versions below; bot tokens need the
+	// equivalent `bot` scopes (e.g. `channels:history`, `chat:write`):
 	//
 	// channels:history - View messages and other content in a user’s public channels
 	// channels:read - View basic information about public channels in a workspace
@@ -33,8 +37,16 @@ func main() {
 	// mpim:history - View messages and other content in a user’s group direct messages
 	// mpim:read - View basic information about a user’s group direct messages
 	// users:read - View people in a workspace
+	// admin.teams:read - (Enterprise Grid only) list workspaces via --enterprise
 	//
-	token := pflag.String("token", "", "Slack token")
+	token := pflag.String("token", "", "Slack token (xoxp- user token or xoxb- bot token)")
+
+	refreshToken := pflag.String("refresh-token", "", "Refresh token for a rotating xoxe- token pair, used instead of --token")
+	clientID := pflag.String("client-id", "", "App client ID, required with --refresh-token")
+	clientSecret := pflag.String("client-secret", "", "App client secret, required with --refresh-token")
+	tokenStore := pflag.String("token-store", "", "Path to the file where the rotated token pair is cached (required with --refresh-token)")
+
+	enterprise := pflag.Bool("enterprise", false, "Enumerate every workspace in the Enterprise Grid org (via admin.teams.list) and clean each one")
 
 	// Limited cleanup to messages and files owned by a specific Slack user.
 	user := pflag.String("user", "", "Limit cleanup to a specific Slack user (username without the `@` sign)")
@@ -48,31 +60,111 @@ func main() {
 
 	commit := pflag.Bool("commit", false, "Perform the actual delete operations. Omitting this flag will perform a DRY-RUN.")
 
+	archive := pflag.String("archive", "", "Archive messages and files to this directory before deleting them")
+
+	concurrency := pflag.Int("concurrency", 4, "Number of concurrent workers to use when deleting messages and files")
+
+	maxRetries := pflag.Int("max-retries", 5, "Maximum number of retries for a single delete operation before giving up")
+
+	tui := pflag.Bool("tui", false, "Review and select messages to delete in an interactive terminal UI instead of deleting everything found")
+
+	config := pflag.String("config", "", "Path to a YAML file of per-channel retention policies, used instead of --before")
+
+	auditLog := pflag.String("audit-log", "", "Append a JSONL audit record for every delete operation to this path")
+	auditWebhook := pflag.String("audit-webhook", "", "POST every audit record to this webhook URL, in addition to --audit-log")
+
 	pflag.Parse()
 
-	// Try loading token from env var if no --token flag was passed.
-	if *token == "" {
-		*token = os.Getenv("MY_SLACK_TOKEN")
+	var auth AuthProvider
+
+	if *refreshToken != "" {
+		if *clientID == "" || *clientSecret == "" || *tokenStore == "" {
+			panic("--refresh-token requires --client-id, --client-secret and --token-store")
+		}
+
+		store := FileTokenStore{Path: *tokenStore}
+		if _, err := store.Load(); err != nil {
+			// Seed the store from --refresh-token on first run.
+			if err := store.Save(storedToken{RefreshToken: *refreshToken}); err != nil {
+				panic(err)
+			}
+		}
+
+		p, err := NewOAuthRefreshProvider(*clientID, *clientSecret, store)
+		if err != nil {
+			panic(err)
+		}
+		auth = p
+	} else {
+		// Try loading token from env var if no --token flag was passed.
 		if *token == "" {
-			panic("--token flag (or MY_SLACK_TOKEN env var) required but missing")
+			*token = os.Getenv("MY_SLACK_TOKEN")
+			if *token == "" {
+				panic("--token flag (or MY_SLACK_TOKEN env var) required but missing")
+			}
 		}
+		auth = StaticTokenProvider(*token)
 	}
 
-	if *before == "" {
+	if *before == "" && *config == "" {
 		pflag.PrintDefaults()
-		panic("--before flag is required but missing")
+		panic("--before or --config flag is required but missing")
 	}
 
+	var policies *Policies
+	if *config != "" {
+		var err error
+		policies, err = LoadPolicies(*config)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	audit, err := NewAuditLogger(*auditLog, *auditWebhook)
+	if err != nil {
+		panic(err)
+	}
+	defer audit.Close()
+
 	s := New(SlackCleanOptions{
-		Before: *before,
-		Token:  *token,
+		Before:      *before,
+		Auth:        auth,
+		Concurrency: *concurrency,
+		MaxRetries:  *maxRetries,
+		Policies:    policies,
+		Audit:       audit,
 	})
 
+	if *enterprise {
+		teams, err := s.AdminTeams(context.Background())
+		if err != nil {
+			panic(err)
+		}
+
+		for _, t := range teams {
+			fmt.Printf("\n=== Team %s (%s) ===\n\n", t.Name, t.ID)
+			runCleanup(s.ForTeam(t.ID), *user, *filter, *archive, *tui, *commit)
+		}
+
+		return
+	}
+
+	runCleanup(s, *user, *filter, *archive, *tui, *commit)
+}
+
+// runCleanup drives one pass of the dry-run collection, archive, TUI review
+// and (if --commit) delete phases against the workspace s is authenticated
+// against.
+func runCleanup(s *SlackClean, user, filter, archive string, tui, commit bool) {
 	// Get all users in workspace.
-	users, userID := s.Users(*user)
+	users, userID := s.Users(user)
+
+	if s.policies != nil {
+		s.policies.ResolveUsers(users)
+	}
 
 	// Get channels.
-	channels := s.Channels(*filter, users)
+	channels := s.Channels(filter, users)
 
 	// Get all files.
 	files := s.Files(userID, channels)
@@ -82,51 +174,135 @@ func main() {
 
 	fmt.Printf("\nFound %d messages and %d files to delete!\n\n", len(messages), len(files))
 
-	if !*commit {
+	if archive != "" {
+		fmt.Printf("Archiving messages and files to %s\n", archive)
+		s.Archive(archive, users, channels, messages, files)
+	}
+
+	if tui {
+		messages = s.TUI(channels, messages)
+		fmt.Printf("\n%d messages selected for deletion\n\n", len(messages))
+	}
+
+	channelNames := make(map[string]string, len(channels))
+	for _, c := range channels {
+		channelNames[c.ID] = c.Name
+	}
+
+	if !commit {
+		s.auditDryRun(messages, files, channelNames)
 		fmt.Printf("Run command again with --commit flag to perform the delete operations!\n\n")
-		os.Exit(0)
+		return
 	}
 
 	// Delete messages.
-	s.DeleteMessages(messages)
+	msgStats := s.DeleteMessages(messages, channelNames)
 
 	// Delete files.
-	s.DeleteFiles(files)
+	fileStats := s.DeleteFiles(files, channelNames)
+
+	msgOK, msgFailed, msgRLHits, msgRLWaited := msgStats.Totals()
+	fileOK, fileFailed, fileRLHits, fileRLWaited := fileStats.Totals()
+	s.audit.Summary(msgOK, fileOK, msgFailed+fileFailed, msgRLHits+fileRLHits, msgRLWaited+fileRLWaited)
 
 	fmt.Printf("\nIt's a Done Deal!\n\n")
 }
 
+// slackClient guards the underlying *slack.Client behind a mutex. It's
+// read concurrently from every worker in s.parallel and written by
+// withRetry on a token refresh, so a bare *slack.Client field would race.
+type slackClient struct {
+	mu sync.Mutex
+	c  *slack.Client
+}
+
+func (sc *slackClient) get() *slack.Client {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.c
+}
+
+func (sc *slackClient) set(c *slack.Client) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.c = c
+}
+
 type SlackClean struct {
-	c            *slack.Client
+	c            *slackClient
+	auth         AuthProvider
 	beforeTS     string
 	beforeTSUnix slack.JSONTime
+	concurrency  int
+	maxRetries   int
+	limiters     map[string]*rateLimiter
+	policies     *Policies
+	audit        *AuditLogger
+	teamID       string // Enterprise Grid team to scope API calls to, if any
 }
 
 type SlackCleanOptions struct {
-	Before string // e.g. 20060102-1504
-	Token  string // e.g  xoxp-...
+	Before      string // e.g. 20060102-1504
+	Auth        AuthProvider
+	Concurrency int // number of concurrent delete workers
+	MaxRetries  int // max retries per delete operation
+	Policies    *Policies
+	Audit       *AuditLogger
 }
 
 func New(o SlackCleanOptions) (s *SlackClean) {
 	s = new(SlackClean)
 
-	t, err := time.Parse("20060102-1504", o.Before)
+	s.policies = o.Policies
+	s.audit = o.Audit
+
+	if o.Before != "" {
+		t, err := time.Parse("20060102-1504", o.Before)
+		if err != nil {
+			panic(err)
+		}
+
+		s.beforeTS = s.TimeToSlackTS(t)
+		s.beforeTSUnix = slack.JSONTime(t.Unix())
+
+		fmt.Printf("Cleaning Slack messages before: %s (Slack TS: %s , Check: %s)\n", prettyDate(t), s.beforeTS, prettyDate(s.beforeTSUnix.Time()))
+	} else {
+		fmt.Printf("Cleaning Slack messages using retention policies from --config\n")
+	}
+
+	s.auth = o.Auth
+
+	token, err := s.auth.Token(context.Background())
 	if err != nil {
 		panic(err)
 	}
 
-	s.beforeTS = s.TimeToSlackTS(t)
-	s.beforeTSUnix = slack.JSONTime(t.Unix())
+	s.c = &slackClient{c: slack.New(token)}
 
-	fmt.Printf("Cleaning Slack messages before: %s (Slack TS: %s , Check: %s)\n", prettyDate(t), s.beforeTS, prettyDate(s.beforeTSUnix.Time()))
+	s.concurrency = o.Concurrency
+	if s.concurrency < 1 {
+		s.concurrency = 1
+	}
 
-	fmt.Printf("Using token: %s\n", o.Token)
+	s.maxRetries = o.MaxRetries
 
-	s.c = slack.New(o.Token)
+	s.limiters = make(map[string]*rateLimiter, len(methodRatesPerSecond))
+	for method, rate := range methodRatesPerSecond {
+		s.limiters[method] = newRateLimiter(rate)
+	}
 
 	return
 }
 
+// ForTeam returns a copy of s scoped to a single Enterprise Grid team, so
+// the same SlackClean can be reused to clean each workspace in the org in
+// turn without one team's results bleeding into another's.
+func (s *SlackClean) ForTeam(teamID string) *SlackClean {
+	scoped := *s
+	scoped.teamID = teamID
+	return &scoped
+}
+
 func (s *SlackClean) SlackTSToTime(ts string) time.Time {
 	parts := strings.Split(ts, ".")
 	sec, _ := strconv.ParseInt(parts[0], 10, 64)
@@ -139,19 +315,15 @@ func (s *SlackClean) TimeToSlackTS(t time.Time) string {
 	return fmt.Sprintf("%s.%s", ts[0:len(ts)-6], ts[len(ts)-6:])
 }
 
-func (s *SlackClean) ratelimitOrPanic(err error) {
-	if !strings.Contains(err.Error(), "rate limit") {
-		panic(err)
-	}
-
-	fmt.Printf("Rate limited, retrying in 1 sec ..\n")
-	time.Sleep(1000 * time.Millisecond)
-}
-
 func (s *SlackClean) Users(user string) (users map[string]string, userID string) {
 	users = make(map[string]string)
 
-	res, err := s.c.GetUsers()
+	var opts []slack.GetUsersOption
+	if s.teamID != "" {
+		opts = append(opts, slack.GetUsersOptionTeamID(s.teamID))
+	}
+
+	res, err := s.c.get().GetUsers(opts...)
 	if err != nil {
 		panic(err)
 	}
@@ -181,6 +353,7 @@ func (s *SlackClean) Users(user string) (users map[string]string, userID string)
 
 func (s *SlackClean) Files(userID string, channels []slack.Channel) (filesToDelete []slack.File) {
 	var found int
+	stats := newMethodStats()
 
 	for _, c := range channels {
 		p := &slack.ListFilesParameters{
@@ -195,15 +368,25 @@ func (s *SlackClean) Files(userID string, channels []slack.Channel) (filesToDele
 
 		for {
 			var res []slack.File
-			var err error
-
-			res, p, err := s.c.ListFiles(*p)
-			if err != nil {
-				s.ratelimitOrPanic(err)
+			var next *slack.ListFilesParameters
+
+			s.limiters["files.list"].Wait()
+			if err := s.withRetry("files.list", stats, func() error {
+				var err error
+				res, next, err = s.c.get().ListFiles(*p)
+				return err
+			}); err != nil {
+				panic(err)
 			}
+			p = next
 
 			for _, f := range res {
-				if f.Created < s.beforeTSUnix {
+				del := f.Created < s.beforeTSUnix
+				if s.policies != nil {
+					del = s.policies.ShouldDeleteFile(c, f)
+				}
+
+				if del {
 					found++
 					fmt.Printf("%04d. Found file %s (created: %s)\n", found, f.Name, prettyDate(f.Created.Time()))
 					filesToDelete = append(filesToDelete, f)
@@ -219,6 +402,7 @@ func (s *SlackClean) Files(userID string, channels []slack.Channel) (filesToDele
 	}
 
 	fmt.Printf("Fetched %d files\n", len(filesToDelete))
+	fmt.Printf("%s\n", stats.Summary("files.list"))
 
 	return
 }
@@ -226,6 +410,7 @@ func (s *SlackClean) Files(userID string, channels []slack.Channel) (filesToDele
 func (s *SlackClean) Channels(filter string, users map[string]string) (channels []slack.Channel) {
 	var found int
 	var cursor string
+	stats := newMethodStats()
 
 	// Create filter regexp.
 	var re *regexp.Regexp
@@ -235,13 +420,20 @@ func (s *SlackClean) Channels(filter string, users map[string]string) (channels
 	}
 
 	for {
-		res, next, err := s.c.GetConversations(&slack.GetConversationsParameters{
-			Types:  []string{"public_channel", "private_channel", "mpim", "im"},
-			Cursor: cursor,
-		})
-		if err != nil {
-			s.ratelimitOrPanic(err)
-			continue
+		var res []slack.Channel
+		var next string
+
+		s.limiters["conversations.list"].Wait()
+		if err := s.withRetry("conversations.list", stats, func() error {
+			var err error
+			res, next, err = s.c.get().GetConversations(&slack.GetConversationsParameters{
+				Types:  []string{"public_channel", "private_channel", "mpim", "im"},
+				Cursor: cursor,
+				TeamID: s.teamID,
+			})
+			return err
+		}); err != nil {
+			panic(err)
 		}
 
 		for _, c := range res {
@@ -284,6 +476,7 @@ func (s *SlackClean) Channels(filter string, users map[string]string) (channels
 	}
 
 	fmt.Printf("Fetched %d channels\n", len(channels))
+	fmt.Printf("%s\n", stats.Summary("conversations.list"))
 
 	return
 }
@@ -292,6 +485,8 @@ func (s *SlackClean) Messages(channels []slack.Channel, userID string) (messages
 	var found int
 	var keep int
 	var total int
+	stats := newMethodStats()
+	replyStats := newMethodStats()
 
 	clean := regexp.MustCompile(`[\r\n\t]+`)
 
@@ -302,29 +497,41 @@ func (s *SlackClean) Messages(channels []slack.Channel, userID string) (messages
 		var last int
 
 		for {
-			res, err := s.c.GetConversationHistory(&slack.GetConversationHistoryParameters{
-				ChannelID: c.ID,
-				Cursor:    cursor,
-				Limit:     1000,
-			})
-			if err != nil {
-				s.ratelimitOrPanic(err)
-				continue
+			var res *slack.GetConversationHistoryResponse
+
+			s.limiters["conversations.history"].Wait()
+			if err := s.withRetry("conversations.history", stats, func() error {
+				var err error
+				res, err = s.c.get().GetConversationHistory(&slack.GetConversationHistoryParameters{
+					ChannelID: c.ID,
+					Cursor:    cursor,
+					Limit:     1000,
+				})
+				return err
+			}); err != nil {
+				panic(err)
 			}
 
 			for _, m := range res.Messages {
 				total++
 
-				if userID != "" {
-					if m.User == userID {
+				if s.policies != nil {
+					if !s.policies.ShouldDeleteMessage(c, m) {
 						keep++
 						continue
 					}
-				}
+				} else {
+					if userID != "" {
+						if m.User == userID {
+							keep++
+							continue
+						}
+					}
 
-				if m.Timestamp > s.beforeTS {
-					keep++
-					continue
+					if m.Timestamp > s.beforeTS {
+						keep++
+						continue
+					}
 				}
 
 				if found == last {
@@ -356,10 +563,17 @@ func (s *SlackClean) Messages(channels []slack.Channel, userID string) (messages
 						Limit:     1000,
 					}
 					for {
-						replies, hasMore, nextCursor, err := s.c.GetConversationReplies(p)
-						if err != nil {
-							s.ratelimitOrPanic(err)
-							continue
+						var replies []slack.Message
+						var hasMore bool
+						var nextCursor string
+
+						s.limiters["conversations.replies"].Wait()
+						if err := s.withRetry("conversations.replies", replyStats, func() error {
+							var err error
+							replies, hasMore, nextCursor, err = s.c.get().GetConversationReplies(p)
+							return err
+						}); err != nil {
+							panic(err)
 						}
 
 						for _, r := range replies {
@@ -401,49 +615,147 @@ func (s *SlackClean) Messages(channels []slack.Channel, userID string) (messages
 	}
 
 	fmt.Printf("Fetched %d messages to delete (kept %d / %d)\n", len(messagesToDelete), keep, total)
+	fmt.Printf("%s\n", stats.Summary("conversations.history"))
+	fmt.Printf("%s\n", replyStats.Summary("conversations.replies"))
 
 	return
 }
 
-func (s *SlackClean) DeleteMessages(messages []slack.Message) {
-	for i, m := range messages {
+// messageAuditRecord builds the AuditRecord for a chat.delete attempt (or,
+// with dryRun set, for a message that would have been deleted).
+func messageAuditRecord(m slack.Message, channelNames map[string]string, dryRun bool) AuditRecord {
+	return AuditRecord{
+		ChannelID:   m.Channel,
+		ChannelName: channelNames[m.Channel],
+		MsgTS:       m.Timestamp,
+		User:        m.User,
+		TextHash:    textHash(m.Text),
+		Bytes:       len(m.Text),
+		DryRun:      dryRun,
+	}
+}
 
-		for {
-			ch, ts, err := s.c.DeleteMessage(m.Channel, m.Timestamp)
+// fileAuditRecord builds the AuditRecord for a files.delete attempt (or,
+// with dryRun set, for a file that would have been deleted).
+func fileAuditRecord(f slack.File, channelNames map[string]string, dryRun bool) AuditRecord {
+	channelID := ""
+	if len(f.Channels) > 0 {
+		channelID = f.Channels[0]
+	}
+
+	return AuditRecord{
+		ChannelID:   channelID,
+		ChannelName: channelNames[channelID],
+		User:        f.User,
+		Bytes:       f.Size,
+		DryRun:      dryRun,
+	}
+}
+
+// auditDryRun records an AuditRecord for every message and file a
+// --commit run would delete, marked DryRun so --audit-log/--audit-webhook
+// can answer "what would have happened" without anything actually being
+// deleted.
+func (s *SlackClean) auditDryRun(messages []slack.Message, files []slack.File, channelNames map[string]string) {
+	for _, m := range messages {
+		s.audit.RecordMessage(messageAuditRecord(m, channelNames, true))
+	}
+
+	for _, f := range files {
+		s.audit.RecordFile(fileAuditRecord(f, channelNames, true))
+	}
+
+	s.audit.Summary(len(messages), len(files), 0, 0, 0)
+}
+
+func (s *SlackClean) DeleteMessages(messages []slack.Message, channelNames map[string]string) *methodStats {
+	stats := newMethodStats()
+
+	s.parallel(len(messages), func(i int) {
+		m := messages[i]
+
+		s.limiters["chat.delete"].Wait()
+
+		err := s.withRetry("chat.delete", stats, func() error {
+			ch, ts, err := s.c.get().DeleteMessage(m.Channel, m.Timestamp)
 			if err != nil {
-				if err.Error() == "message_not_found" {
-					break
-				}
-				s.ratelimitOrPanic(err)
-				continue
+				return err
 			}
-
 			fmt.Printf("%04d. Deleted message ID %s in channel ID %s\n", i+1, ts, ch)
-			break
+			return nil
+		})
+
+		rec := messageAuditRecord(m, channelNames, false)
+		if err != nil {
+			rec.Error = err.Error()
 		}
+		s.audit.RecordMessage(rec)
 
-		time.Sleep(50 * time.Millisecond)
-	}
+		if err != nil && err.Error() != "message_not_found" {
+			fmt.Printf("%04d. Giving up on message ID %s in channel ID %s: %s\n", i+1, m.Timestamp, m.Channel, err)
+		}
+	})
+
+	fmt.Printf("\n%s\n", stats.Summary("chat.delete"))
+
+	return stats
 }
 
-func (s *SlackClean) DeleteFiles(files []slack.File) {
-	for i, f := range files {
-		for {
-			err := s.c.DeleteFile(f.ID)
+func (s *SlackClean) DeleteFiles(files []slack.File, channelNames map[string]string) *methodStats {
+	stats := newMethodStats()
+
+	s.parallel(len(files), func(i int) {
+		f := files[i]
+
+		s.limiters["files.delete"].Wait()
+
+		err := s.withRetry("files.delete", stats, func() error {
+			err := s.c.get().DeleteFile(f.ID)
 			if err != nil {
-				if err.Error() == "file_not_found" {
-					break
-				}
-				s.ratelimitOrPanic(err)
-				continue
+				return err
 			}
-
 			fmt.Printf("%04d. Deleted file named %s (created: %s)\n", i+1, f.Name, prettyDate(f.Created.Time()))
-			break
+			return nil
+		})
+
+		rec := fileAuditRecord(f, channelNames, false)
+		if err != nil {
+			rec.Error = err.Error()
 		}
+		s.audit.RecordFile(rec)
 
-		time.Sleep(50 * time.Millisecond)
+		if err != nil && err.Error() != "file_not_found" {
+			fmt.Printf("%04d. Giving up on file %s: %s\n", i+1, f.Name, err)
+		}
+	})
+
+	fmt.Printf("\n%s\n", stats.Summary("files.delete"))
+
+	return stats
+}
+
+// parallel runs fn(i) for i in [0, n) across s.concurrency workers and
+// blocks until all of them complete.
+func (s *SlackClean) parallel(n int, fn func(i int)) {
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < s.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
 	}
+	close(jobs)
+
+	wg.Wait()
 }
 
 func prettyDate(t time.Time) string {
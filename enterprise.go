@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Team is one workspace in an Enterprise Grid organization, as returned by
+// admin.teams.list.
+type Team struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// AdminTeams enumerates every workspace in the Enterprise Grid org the
+// current token belongs to, paging through admin.teams.list. It requires
+// a token with the admin.teams:read scope.
+func (s *SlackClean) AdminTeams(ctx context.Context) ([]Team, error) {
+	token, err := s.auth.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var teams []Team
+	var cursor string
+
+	for {
+		form := url.Values{"limit": {"100"}}
+		if cursor != "" {
+			form.Set("cursor", cursor)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://slack.com/api/admin.teams.list?"+form.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var out struct {
+			OK       bool   `json:"ok"`
+			Error    string `json:"error"`
+			Teams    []Team `json:"teams"`
+			Metadata struct {
+				NextCursor string `json:"next_cursor"`
+			} `json:"response_metadata"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&out)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if !out.OK {
+			return nil, fmt.Errorf("admin.teams.list: %s", out.Error)
+		}
+
+		teams = append(teams, out.Teams...)
+
+		if out.Metadata.NextCursor == "" {
+			break
+		}
+		cursor = out.Metadata.NextCursor
+	}
+
+	fmt.Printf("Found %d teams in Enterprise Grid org\n", len(teams))
+
+	return teams, nil
+}
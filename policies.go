@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"gopkg.in/yaml.v3"
+)
+
+// Policy is a single retention rule. Rules are evaluated in file order;
+// for each message or file, the first policy whose Channels filter matches
+// its channel and whose Users filter matches its author decides whether it
+// gets deleted.
+type Policy struct {
+	Channels []string `yaml:"channels"`
+	Users    []string `yaml:"users"`
+
+	KeepPinned               bool `yaml:"keep_pinned"`
+	KeepStarred              bool `yaml:"keep_starred"`
+	KeepThreadsWithReactions bool `yaml:"keep_threads_with_reactions"`
+
+	OlderThan string `yaml:"older_than"` // e.g. "30d", "24h"
+
+	OnlySubtypes []string `yaml:"only_subtypes"`
+
+	MinReactionsToKeep int `yaml:"min_reactions_to_keep"`
+
+	channelRe  *regexp.Regexp
+	userSet    map[string]bool
+	subtypeSet map[string]bool
+	olderThan  time.Duration
+}
+
+// Policies is the top-level document parsed from a --config YAML file.
+type Policies struct {
+	Policies []Policy `yaml:"policies"`
+}
+
+// LoadPolicies reads and compiles the policy file at path.
+func LoadPolicies(path string) (*Policies, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Policies
+	if err := yaml.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	for i := range p.Policies {
+		if err := p.Policies[i].compile(); err != nil {
+			return nil, fmt.Errorf("policy %d: %w", i, err)
+		}
+	}
+
+	return &p, nil
+}
+
+func (p *Policy) compile() error {
+	if len(p.Channels) > 0 {
+		p.channelRe = regexp.MustCompile(`(?i)(` + strings.Join(p.Channels, "|") + `)`)
+	}
+
+	if len(p.OnlySubtypes) > 0 {
+		p.subtypeSet = make(map[string]bool, len(p.OnlySubtypes))
+		for _, st := range p.OnlySubtypes {
+			p.subtypeSet[st] = true
+		}
+	}
+
+	if p.OlderThan != "" {
+		d, err := parseOlderThan(p.OlderThan)
+		if err != nil {
+			return err
+		}
+		p.olderThan = d
+	}
+
+	return nil
+}
+
+// parseOlderThan parses durations like "30d" or "90d" in addition to the
+// units time.ParseDuration already understands ("24h", "15m").
+func parseOlderThan(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid older_than %q: %w", s, err)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// ResolveUsers maps each policy's configured Users (workspace usernames,
+// per the --config documentation) to the Slack user IDs messages and
+// files are actually tagged with, using the {id: name} map Users()
+// builds. It must be called once the target workspace's user list is
+// known, and again per team when --enterprise is in effect, since the
+// same username can map to a different ID in each workspace.
+func (p *Policies) ResolveUsers(users map[string]string) {
+	nameToID := make(map[string]string, len(users))
+	for id, name := range users {
+		nameToID[name] = id
+	}
+
+	for i := range p.Policies {
+		policy := &p.Policies[i]
+		if len(policy.Users) == 0 {
+			continue
+		}
+
+		policy.userSet = make(map[string]bool, len(policy.Users))
+		for _, u := range policy.Users {
+			id, ok := nameToID[u]
+			if !ok {
+				fmt.Printf("Warning: policy user %q not found in workspace, ignoring\n", u)
+				continue
+			}
+			policy.userSet[id] = true
+		}
+	}
+}
+
+// match returns the first policy whose channel/user filters apply to c,
+// or nil if no policy covers it.
+func (p *Policies) match(c slack.Channel, userID string) *Policy {
+	for i := range p.Policies {
+		policy := &p.Policies[i]
+
+		if policy.channelRe != nil && !policy.channelRe.MatchString(c.Name) {
+			continue
+		}
+
+		if policy.userSet != nil && !policy.userSet[userID] {
+			continue
+		}
+
+		return policy
+	}
+
+	return nil
+}
+
+// ShouldDeleteMessage decides whether m in channel c should be deleted
+// under these policies. Channels with no matching policy are left alone.
+func (p *Policies) ShouldDeleteMessage(c slack.Channel, m slack.Message) bool {
+	policy := p.match(c, m.User)
+	if policy == nil {
+		return false
+	}
+
+	if policy.KeepPinned && len(m.PinnedTo) > 0 {
+		return false
+	}
+
+	if policy.KeepStarred && m.IsStarred {
+		return false
+	}
+
+	if policy.KeepThreadsWithReactions && m.ThreadTimestamp == m.Timestamp && totalReactions(m) > 0 {
+		return false
+	}
+
+	if policy.MinReactionsToKeep > 0 && totalReactions(m) >= policy.MinReactionsToKeep {
+		return false
+	}
+
+	if policy.subtypeSet != nil && !policy.subtypeSet[m.SubType] {
+		return false
+	}
+
+	if policy.olderThan > 0 {
+		ts, err := parseSlackTS(m.Timestamp)
+		if err == nil && time.Since(ts) < policy.olderThan {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ShouldDeleteFile decides whether f in channel c should be deleted under
+// these policies.
+func (p *Policies) ShouldDeleteFile(c slack.Channel, f slack.File) bool {
+	policy := p.match(c, f.User)
+	if policy == nil {
+		return false
+	}
+
+	if policy.olderThan > 0 && time.Since(f.Created.Time()) < policy.olderThan {
+		return false
+	}
+
+	return true
+}
+
+func totalReactions(m slack.Message) int {
+	var n int
+	for _, r := range m.Reactions {
+		n += r.Count
+	}
+	return n
+}
+
+func parseSlackTS(ts string) (time.Time, error) {
+	parts := strings.SplitN(ts, ".", 2)
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, 0), nil
+}
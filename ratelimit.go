@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Slack's documented per-method rate-limit tiers (api.slack.com/docs/rate-limits),
+// converted from "N+ requests per minute" to a sustained per-second rate and
+// rounded down for headroom so a run with a high --concurrency doesn't trip
+// the API's burst limits.
+var methodRatesPerSecond = map[string]float64{
+	"chat.delete":           0.8,  // Tier 3, 50+/min
+	"conversations.history": 0.8,  // Tier 3, 50+/min
+	"conversations.replies": 0.8,  // Tier 3, 50+/min
+	"conversations.list":    0.3,  // Tier 2, 20+/min
+	"files.list":            0.3,  // Tier 2, 20+/min
+	"files.delete":          0.15, // files.* methods aren't in the published tier table; stay conservative
+}
+
+// rateLimiter is a simple token-bucket limiter for a single Slack API
+// method, refilled at a fixed rate.
+type rateLimiter struct {
+	tokens chan struct{}
+	stop   chan struct{}
+}
+
+func newRateLimiter(ratePerSecond float64) *rateLimiter {
+	if ratePerSecond <= 0 {
+		ratePerSecond = 1
+	}
+
+	r := &rateLimiter{
+		tokens: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+	}
+
+	interval := time.Duration(float64(time.Second) / ratePerSecond)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case r.tokens <- struct{}{}:
+			default:
+			}
+
+			select {
+			case <-ticker.C:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+
+	return r
+}
+
+func (r *rateLimiter) Wait() {
+	<-r.tokens
+}
+
+// methodStats accumulates per-method throughput and rate-limit counters so
+// a run can print a summary of how much time it spent waiting vs. working.
+type methodStats struct {
+	mu              sync.Mutex
+	succeeded       int
+	failed          int
+	rateLimitHits   int
+	rateLimitWaited time.Duration
+	start           time.Time
+}
+
+func newMethodStats() *methodStats {
+	return &methodStats{start: time.Now()}
+}
+
+func (m *methodStats) recordSuccess() {
+	m.mu.Lock()
+	m.succeeded++
+	m.mu.Unlock()
+}
+
+func (m *methodStats) recordFailure() {
+	m.mu.Lock()
+	m.failed++
+	m.mu.Unlock()
+}
+
+func (m *methodStats) recordRateLimit(wait time.Duration) {
+	m.mu.Lock()
+	m.rateLimitHits++
+	m.rateLimitWaited += wait
+	m.mu.Unlock()
+}
+
+// Totals returns the accumulated counters, for callers (like the audit
+// log) that need the raw numbers rather than the formatted Summary line.
+func (m *methodStats) Totals() (succeeded, failed, rateLimitHits int, rateLimitWaited time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.succeeded, m.failed, m.rateLimitHits, m.rateLimitWaited
+}
+
+func (m *methodStats) Summary(method string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elapsed := time.Since(m.start)
+	rate := float64(m.succeeded) / elapsed.Seconds()
+
+	return fmt.Sprintf(
+		"%-25s succeeded: %-6d failed: %-6d rate-limited: %-6d (waited %s)  --  %.1f/s",
+		method, m.succeeded, m.failed, m.rateLimitHits, m.rateLimitWaited.Round(time.Millisecond), rate,
+	)
+}
+
+// notFoundErrors are permanent, idempotent "already gone" results.
+// Retrying them through the backoff ladder would just waste time, so
+// withRetry returns them to the caller immediately instead.
+var notFoundErrors = map[string]bool{
+	"message_not_found": true,
+	"file_not_found":    true,
+}
+
+// withRetry calls fn, retrying on rate limits (honoring the API's
+// Retry-After) and transient errors (exponential backoff with jitter) up
+// to s.maxRetries times. notFoundErrors are returned immediately so
+// callers can treat "already deleted" as a non-error.
+func (s *SlackClean) withRetry(method string, stats *methodStats, fn func() error) error {
+	backoff := 500 * time.Millisecond
+	attempt := 0
+
+	for {
+		err := fn()
+		if err == nil {
+			stats.recordSuccess()
+			return nil
+		}
+
+		if notFoundErrors[err.Error()] {
+			return err
+		}
+
+		if isTokenExpiredErr(err) {
+			fmt.Printf("Token expired calling %s, refreshing ..\n", method)
+			if p, ok := s.auth.(*OAuthRefreshProvider); ok {
+				// Drop the cached token so Token refreshes instead of
+				// handing back the one the API just rejected.
+				p.mu.Lock()
+				p.current.AccessToken = ""
+				p.mu.Unlock()
+			}
+			token, refreshErr := s.auth.Token(context.Background())
+			if refreshErr != nil {
+				stats.recordFailure()
+				return refreshErr
+			}
+			s.c.set(slack.New(token))
+			continue
+		}
+
+		var rlErr *slack.RateLimitedError
+		if errors.As(err, &rlErr) {
+			stats.recordRateLimit(rlErr.RetryAfter)
+			fmt.Printf("Rate limited on %s, waiting %s (Retry-After)\n", method, rlErr.RetryAfter)
+			time.Sleep(rlErr.RetryAfter)
+			continue
+		}
+
+		if attempt >= s.maxRetries {
+			stats.recordFailure()
+			return err
+		}
+
+		sleep := backoff + time.Duration(rand.Int63n(int64(backoff)))
+		fmt.Printf("Error calling %s (attempt %d/%d), retrying in %s: %s\n", method, attempt+1, s.maxRetries, sleep, err)
+		time.Sleep(sleep)
+
+		attempt++
+		backoff *= 2
+	}
+}
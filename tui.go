@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	"github.com/slack-go/slack"
+)
+
+// tuiChannel groups the messages found for one channel, in display order.
+type tuiChannel struct {
+	channel  slack.Channel
+	messages []slack.Message
+	// keep[i] is true when messages[i] has been marked to keep instead of
+	// delete. New entries default to false (i.e. delete).
+	keep []bool
+}
+
+// TUI presents a three-pane review of the candidate messages (channels on
+// the left, messages for the selected channel in the middle, full text and
+// thread replies on the right) and lets the user toggle individual
+// messages to keep before committing. It returns only the messages the
+// user left marked for deletion.
+func (s *SlackClean) TUI(channels []slack.Channel, messages []slack.Message) []slack.Message {
+	byChannel := make(map[string]*tuiChannel)
+	for _, c := range channels {
+		byChannel[c.ID] = &tuiChannel{channel: c}
+	}
+
+	repliesByParent := make(map[string][]slack.Message)
+	for _, m := range messages {
+		if m.ThreadTimestamp != "" && m.ThreadTimestamp != m.Timestamp {
+			repliesByParent[m.ThreadTimestamp] = append(repliesByParent[m.ThreadTimestamp], m)
+			continue
+		}
+		if tc, ok := byChannel[m.Channel]; ok {
+			tc.messages = append(tc.messages, m)
+			tc.keep = append(tc.keep, false)
+		}
+	}
+
+	var tcs []*tuiChannel
+	for _, c := range channels {
+		if tc := byChannel[c.ID]; len(tc.messages) > 0 {
+			tcs = append(tcs, tc)
+		}
+	}
+	sort.Slice(tcs, func(i, j int) bool { return tcs[i].channel.Name < tcs[j].channel.Name })
+
+	app := tview.NewApplication()
+
+	channelList := tview.NewList().ShowSecondaryText(false)
+	channelList.SetBorder(true).SetTitle(" Channels ")
+
+	messageList := tview.NewList().ShowSecondaryText(true)
+	messageList.SetBorder(true).SetTitle(" Messages (space: toggle keep, /: filter, g: jump to date) ")
+
+	preview := tview.NewTextView().SetDynamicColors(true).SetWrap(true)
+	preview.SetBorder(true).SetTitle(" Preview ")
+
+	filterField := tview.NewInputField().SetLabel("Filter: ")
+	dateField := tview.NewInputField().SetLabel("Jump to date (YYYYMMDD-HHII): ")
+
+	root := tview.NewFlex()
+	left := tview.NewFlex().SetDirection(tview.FlexRow).AddItem(channelList, 0, 1, true)
+	mid := tview.NewFlex().SetDirection(tview.FlexRow).AddItem(messageList, 0, 1, false)
+	root.AddItem(left, 0, 1, true).AddItem(mid, 0, 2, false).AddItem(preview, 0, 2, false)
+
+	pages := tview.NewPages().AddPage("main", root, true, true)
+
+	clean := regexp.MustCompile(`[\r\n\t]+`)
+
+	var activeChannel *tuiChannel
+	var activeFilter *regexp.Regexp
+
+	// visible maps a position in the (possibly filtered) messageList
+	// widget back to its index in activeChannel.messages/keep, since a
+	// regex filter can make those two indices diverge.
+	var visible []int
+
+	refreshMessageList := func() {
+		messageList.Clear()
+		visible = visible[:0]
+
+		if activeChannel == nil {
+			return
+		}
+
+		for i, m := range activeChannel.messages {
+			if activeFilter != nil && !activeFilter.MatchString(m.Text) {
+				continue
+			}
+
+			visible = append(visible, i)
+
+			mark := "[red]delete[-]"
+			if activeChannel.keep[i] {
+				mark = "[green]keep[-]"
+			}
+			preview := clean.ReplaceAllString(m.Text, " ")
+			if len(preview) > 60 {
+				preview = preview[:56] + " ..."
+			}
+			messageList.AddItem(fmt.Sprintf("%s  %s", mark, preview), prettyDate(s.SlackTSToTime(m.Timestamp)), 0, nil)
+		}
+	}
+
+	showPreview := func(pos int) {
+		if activeChannel == nil || pos < 0 || pos >= len(visible) {
+			preview.SetText("")
+			return
+		}
+		m := activeChannel.messages[visible[pos]]
+		text := fmt.Sprintf("[::b]%s[-:-:-]\n\n%s\n", prettyDate(s.SlackTSToTime(m.Timestamp)), m.Text)
+		for _, r := range repliesByParent[m.Timestamp] {
+			text += fmt.Sprintf("\n  [::d]%s[-:-:-]  %s", prettyDate(s.SlackTSToTime(r.Timestamp)), r.Text)
+		}
+		preview.SetText(text)
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		channelList.AddItem(tc.channel.Name, fmt.Sprintf("%d messages", len(tc.messages)), 0, func() {
+			activeChannel = tc
+			refreshMessageList()
+			app.SetFocus(messageList)
+		})
+	}
+
+	messageList.SetChangedFunc(func(i int, _, _ string, _ rune) {
+		showPreview(i)
+	})
+
+	messageList.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case ' ':
+			if activeChannel != nil {
+				pos := messageList.GetCurrentItem()
+				if pos >= 0 && pos < len(visible) {
+					i := visible[pos]
+					activeChannel.keep[i] = !activeChannel.keep[i]
+					refreshMessageList()
+					messageList.SetCurrentItem(pos)
+				}
+			}
+			return nil
+		case '/':
+			app.SetFocus(filterField)
+			return nil
+		case 'g':
+			app.SetFocus(dateField)
+			return nil
+		}
+		return event
+	})
+
+	filterField.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			pattern := filterField.GetText()
+			if pattern == "" {
+				activeFilter = nil
+			} else if re, err := regexp.Compile(pattern); err == nil {
+				activeFilter = re
+			}
+			refreshMessageList()
+		}
+		app.SetFocus(messageList)
+	})
+
+	// dateField jumps the selection to the most recent visible message at
+	// or before the entered timestamp, accepting the same "20060102-1504"
+	// format as --before. Messages arrive newest-first (per
+	// conversations.history), so this scans forward from the top looking
+	// for the first entry that has fallen to or past the target date.
+	dateField.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			if t, err := time.Parse("20060102-1504", dateField.GetText()); err == nil && activeChannel != nil {
+				for pos, i := range visible {
+					if !s.SlackTSToTime(activeChannel.messages[i].Timestamp).After(t) {
+						messageList.SetCurrentItem(pos)
+						break
+					}
+				}
+			}
+			dateField.SetText("")
+		}
+		app.SetFocus(messageList)
+	})
+
+	var committed bool
+
+	app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyCtrlS {
+			committed = true
+			app.Stop()
+			return nil
+		}
+		if event.Key() == tcell.KeyCtrlC {
+			app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	if len(tcs) > 0 {
+		activeChannel = tcs[0]
+		refreshMessageList()
+	}
+
+	if err := app.SetRoot(pages, true).SetFocus(channelList).Run(); err != nil {
+		panic(err)
+	}
+
+	if !committed {
+		return nil
+	}
+
+	var selected []slack.Message
+	for _, tc := range tcs {
+		for i, m := range tc.messages {
+			if !tc.keep[i] {
+				selected = append(selected, m)
+				selected = append(selected, repliesByParent[m.Timestamp]...)
+			}
+		}
+	}
+
+	return selected
+}